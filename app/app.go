@@ -0,0 +1,364 @@
+// Copyright 2020 Northern.tech AS
+//
+//    Licensed under the Apache License, Version 2.0 (the "License");
+//    you may not use this file except in compliance with the License.
+//    You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS,
+//    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//    See the License for the specific language governing permissions and
+//    limitations under the License.
+
+package app
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/nats-io/nats.go"
+	"github.com/vmihailenco/msgpack/v5"
+
+	natsClient "github.com/mendersoftware/deviceconnect/client/nats"
+	"github.com/mendersoftware/deviceconnect/model"
+	"github.com/mendersoftware/deviceconnect/store"
+)
+
+// App is the interface of the core business logic of deviceconnect.
+type App interface {
+	HealthCheck(ctx context.Context) error
+
+	ProvisionTenant(ctx context.Context, tenant *model.Tenant) error
+	ProvisionDevice(ctx context.Context, tenantID string, device *model.Device) error
+	DeleteDevice(ctx context.Context, tenantID string, deviceID string) error
+	GetDevice(ctx context.Context, tenantID string, deviceID string) (*model.Device, error)
+	// UpdateDeviceStatus and UpdateUserSessionStatus take the mastership
+	// term returned by the Mastership.Elect call that established the
+	// caller's ownership of deviceID, and abort with
+	// ErrMastershipTermStale if that term is no longer current.
+	// PrepareUserSession and SubscribeMessagesFromDevice/Management also
+	// call Elect, but do not return the term they were granted, and the
+	// term is not recorded on the Session.
+	UpdateDeviceStatus(
+		ctx context.Context, tenantID string, deviceID string, status string, term uint64,
+	) error
+	RotateDeviceKey(ctx context.Context, tenantID string, deviceID string, newPub string) error
+
+	PrepareUserSession(
+		ctx context.Context, tenantID string, userID string, deviceID string,
+	) (*model.Session, error)
+	UpdateUserSessionStatus(
+		ctx context.Context, tenantID string, deviceID string, sessionID string, status string, term uint64,
+	) error
+
+	// PublishMessageFromDevice/Management and SubscribeMessagesFromDevice/
+	// Management take a sessionID so they can route onto the per-session
+	// subject (see natsClient.SessionSubject) the JetStream stream is
+	// actually configured to accept - the tenant/device alone is not
+	// enough to address a session's messages.
+	PublishMessageFromDevice(
+		ctx context.Context, tenantID string, deviceID string, sessionID string, message *model.Message,
+	) error
+	PublishMessageFromManagement(
+		ctx context.Context, tenantID string, deviceID string, sessionID string, message *model.Message,
+	) error
+	SubscribeMessagesFromDevice(
+		ctx context.Context, tenantID string, deviceID string, sessionID string, opts SubscribeOptions,
+	) (<-chan *model.Message, <-chan SubscriptionEvent, error)
+	SubscribeMessagesFromManagement(
+		ctx context.Context, tenantID string, deviceID string, sessionID string, opts SubscribeOptions,
+	) (<-chan *model.Message, <-chan SubscriptionEvent, error)
+}
+
+// DeviceConnectApp is the default App implementation.
+type DeviceConnectApp struct {
+	store  store.DataStore
+	client natsClient.ClientInterface
+
+	mastership    Mastership
+	authenticator DeviceAuthenticator
+}
+
+// NewDeviceConnectApp returns a new App backed by store and client. When
+// deviceconnect is run as a single replica - the common case - no further
+// setup is required; mastership is local and always granted.
+func NewDeviceConnectApp(store store.DataStore, client natsClient.ClientInterface) *DeviceConnectApp {
+	return &DeviceConnectApp{
+		store:         store,
+		client:        client,
+		mastership:    newSingleReplicaMastership(),
+		authenticator: newNatsDeviceAuthenticator(client),
+	}
+}
+
+// WithMastership replaces the default single-replica Mastership, e.g. with
+// a NatsMastership, for deployments running more than one replica.
+func (a *DeviceConnectApp) WithMastership(m Mastership) *DeviceConnectApp {
+	a.mastership = m
+	return a
+}
+
+// WithDeviceAuthenticator replaces the default NATS-challenge
+// DeviceAuthenticator, primarily so tests can inject a fake one.
+func (a *DeviceConnectApp) WithDeviceAuthenticator(auth DeviceAuthenticator) *DeviceConnectApp {
+	a.authenticator = auth
+	return a
+}
+
+func (a *DeviceConnectApp) HealthCheck(ctx context.Context) error {
+	return a.store.Ping(ctx)
+}
+
+func (a *DeviceConnectApp) ProvisionTenant(ctx context.Context, tenant *model.Tenant) error {
+	return a.store.ProvisionTenant(ctx, tenant.TenantID)
+}
+
+func (a *DeviceConnectApp) ProvisionDevice(
+	ctx context.Context, tenantID string, device *model.Device,
+) error {
+	return a.store.ProvisionDevice(ctx, tenantID, device.ID, device.PublicKey)
+}
+
+func (a *DeviceConnectApp) DeleteDevice(ctx context.Context, tenantID string, deviceID string) error {
+	return a.store.DeleteDevice(ctx, tenantID, deviceID)
+}
+
+func (a *DeviceConnectApp) GetDevice(
+	ctx context.Context, tenantID string, deviceID string,
+) (*model.Device, error) {
+	device, err := a.store.GetDevice(ctx, tenantID, deviceID)
+	if err != nil {
+		return nil, err
+	} else if device == nil {
+		return nil, ErrDeviceNotFound
+	}
+	return device, nil
+}
+
+func (a *DeviceConnectApp) UpdateDeviceStatus(
+	ctx context.Context, tenantID string, deviceID string, status string, term uint64,
+) error {
+	if err := a.assertMastershipTerm(tenantID, deviceID, term); err != nil {
+		return err
+	}
+	return a.store.UpdateDeviceStatus(ctx, tenantID, deviceID, status)
+}
+
+// assertMastershipTerm returns ErrMastershipTermStale if term is no longer
+// the term this replica was granted for (tenantID, deviceID) by the
+// mastership.Elect call that preceded the write, i.e. some other replica
+// has since taken over the device. CurrentTerm returning ok=false - the
+// lease has expired or the backing KV entry is gone - is treated as stale
+// too: it is exactly the post-expiry window before a new master's lease
+// lands, and a replica that just lost the renewal race must not be able
+// to slip a write through it.
+func (a *DeviceConnectApp) assertMastershipTerm(tenantID string, deviceID string, term uint64) error {
+	current, ok := a.mastership.CurrentTerm(mastershipKey(tenantID, deviceID))
+	if !ok || current != term {
+		return ErrMastershipTermStale
+	}
+	return nil
+}
+
+// RotateDeviceKey replaces the public key a device authenticates with in
+// PrepareUserSession, e.g. after the device generates a new keypair.
+func (a *DeviceConnectApp) RotateDeviceKey(
+	ctx context.Context, tenantID string, deviceID string, newPub string,
+) error {
+	return a.store.RotateDeviceKey(ctx, tenantID, deviceID, newPub)
+}
+
+// PrepareUserSession checks that the device is connected, challenges it
+// to prove it holds the private key matching its provisioned public key,
+// then becomes (or confirms it already is) the master replica for the
+// device before handing out a session, so that the replica the user's
+// WebSocket lands on is also the one that owns the device's NATS
+// subscription.
+func (a *DeviceConnectApp) PrepareUserSession(
+	ctx context.Context, tenantID string, userID string, deviceID string,
+) (*model.Session, error) {
+	device, err := a.store.GetDevice(ctx, tenantID, deviceID)
+	if err != nil {
+		return nil, err
+	} else if device == nil {
+		return nil, ErrDeviceNotFound
+	} else if device.Status != model.DeviceStatusConnected {
+		return nil, ErrDeviceNotConnected
+	}
+
+	// Devices provisioned before this field was introduced have no
+	// public key on record yet; they are exempted until they rotate one
+	// in, rather than locking existing fleets out of sessions.
+	if device.PublicKey != "" {
+		if err := a.authenticator.Challenge(ctx, tenantID, deviceID, userID, device.PublicKey); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, _, err := a.mastership.Elect(ctx, mastershipKey(tenantID, deviceID)); err != nil {
+		return nil, fmt.Errorf("failed to acquire mastership for device: %w", err)
+	}
+
+	return a.store.UpsertSession(ctx, tenantID, userID, deviceID)
+}
+
+// ResumeUserSession replays the device-side messages a reconnecting
+// management client missed, starting right after lastSeq, by pulling
+// them from the tenant's JetStream stream. It requires the app to have
+// been constructed with a JetStream-mode client; legacy core-NATS
+// deployments return ErrJetStreamNotConfigured, since nothing was
+// persisted to replay.
+func (a *DeviceConnectApp) ResumeUserSession(
+	ctx context.Context, tenantID string, sessionID string, lastSeq uint64,
+) (<-chan *model.Message, error) {
+	jsClient, ok := a.client.(natsClient.JetStreamClientInterface)
+	if !ok {
+		return nil, ErrJetStreamNotConfigured
+	}
+
+	session, err := a.store.GetSession(ctx, tenantID, sessionID)
+	if err != nil {
+		return nil, err
+	} else if session == nil {
+		return nil, ErrSessionNotFound
+	}
+
+	out := make(chan *model.Message, defaultSubscriptionCapacity)
+	subject := natsClient.SessionSubject(session.DeviceID, sessionID, "device")
+	stop, err := jsClient.Resume(ctx, subject, lastSeq, func(msg *nats.Msg) {
+		message := &model.Message{}
+		if err := msgpack.Unmarshal(msg.Data, message); err != nil {
+			return
+		}
+		out <- message
+		if meta, err := msg.Metadata(); err == nil {
+			_ = a.store.UpdateSessionSeq(ctx, tenantID, sessionID, "device", meta.Sequence.Stream)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	go func() {
+		<-ctx.Done()
+		_ = stop()
+		close(out)
+	}()
+
+	return out, nil
+}
+
+func (a *DeviceConnectApp) UpdateUserSessionStatus(
+	ctx context.Context, tenantID string, deviceID string, sessionID string, status string, term uint64,
+) error {
+	if err := a.assertMastershipTerm(tenantID, deviceID, term); err != nil {
+		return err
+	}
+	return a.store.UpdateSessionStatus(ctx, tenantID, sessionID, status)
+}
+
+func (a *DeviceConnectApp) PublishMessageFromDevice(
+	ctx context.Context, tenantID string, deviceID string, sessionID string, message *model.Message,
+) error {
+	return a.publishMessage(deviceID, sessionID, "device", message)
+}
+
+func (a *DeviceConnectApp) PublishMessageFromManagement(
+	ctx context.Context, tenantID string, deviceID string, sessionID string, message *model.Message,
+) error {
+	return a.publishMessage(deviceID, sessionID, "mgmt", message)
+}
+
+func (a *DeviceConnectApp) publishMessage(
+	deviceID string, sessionID string, side string, message *model.Message,
+) error {
+	subject := natsClient.SessionSubject(deviceID, sessionID, side)
+
+	if jsClient, ok := a.client.(natsClient.JetStreamClientInterface); ok {
+		data, err := msgpack.Marshal(message)
+		if err != nil {
+			return err
+		}
+		seq, err := jsClient.PublishSeq(subject, data)
+		if err != nil {
+			return err
+		}
+		message.Seq = seq
+		return nil
+	}
+
+	data, err := msgpack.Marshal(message)
+	if err != nil {
+		return err
+	}
+	return a.client.Publish(subject, data)
+}
+
+func (a *DeviceConnectApp) SubscribeMessagesFromDevice(
+	ctx context.Context, tenantID string, deviceID string, sessionID string, opts SubscribeOptions,
+) (<-chan *model.Message, <-chan SubscriptionEvent, error) {
+	return a.subscribeMessages(ctx, tenantID, deviceID, sessionID, "device", opts)
+}
+
+func (a *DeviceConnectApp) SubscribeMessagesFromManagement(
+	ctx context.Context, tenantID string, deviceID string, sessionID string, opts SubscribeOptions,
+) (<-chan *model.Message, <-chan SubscriptionEvent, error) {
+	return a.subscribeMessages(ctx, tenantID, deviceID, sessionID, "mgmt", opts)
+}
+
+// subscribeMessages subscribes to the per-session NATS subject for
+// (deviceID, sessionID, side) and forwards decoded messages on the
+// returned channel only for as long as this replica holds mastership of
+// the device; once the lease is lost, or the consumer falls behind badly
+// enough to trip opts.OverflowPolicy's cancel policy, the subscription is
+// torn down and both channels are closed so the caller can close the
+// corresponding WebSocket.
+func (a *DeviceConnectApp) subscribeMessages(
+	ctx context.Context, tenantID string, deviceID string, sessionID string, side string, opts SubscribeOptions,
+) (<-chan *model.Message, <-chan SubscriptionEvent, error) {
+	key := mastershipKey(tenantID, deviceID)
+	term, lostCh, err := a.mastership.Elect(ctx, key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to acquire mastership for device: %w", err)
+	}
+
+	out := make(chan *model.Message, opts.capacity())
+	events := make(chan SubscriptionEvent, 1)
+	canceled := make(chan struct{})
+
+	subject := natsClient.SessionSubject(deviceID, sessionID, side)
+	unsubscribe, err := a.client.Subscribe(subject, func(msg *nats.Msg) {
+		select {
+		case <-canceled:
+			return
+		default:
+		}
+		if current, ok := a.mastership.CurrentTerm(key); ok && current != term {
+			return
+		}
+		message := &model.Message{}
+		if err := msgpack.Unmarshal(msg.Data, message); err != nil {
+			return
+		}
+		if !enqueue(tenantID, out, events, opts, message) {
+			close(canceled)
+		}
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	go func() {
+		select {
+		case <-lostCh:
+		case <-canceled:
+		}
+		_ = unsubscribe()
+		close(out)
+		close(events)
+	}()
+
+	return out, events, nil
+}
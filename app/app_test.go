@@ -24,6 +24,8 @@ import (
 	"github.com/stretchr/testify/mock"
 	"github.com/vmihailenco/msgpack/v5"
 
+	app_mocks "github.com/mendersoftware/deviceconnect/app/mocks"
+	natsClient "github.com/mendersoftware/deviceconnect/client/nats"
 	nats_mocks "github.com/mendersoftware/deviceconnect/client/nats/mocks"
 	"github.com/mendersoftware/deviceconnect/model"
 	store_mocks "github.com/mendersoftware/deviceconnect/store/mocks"
@@ -73,6 +75,7 @@ func TestProvisionDevice(t *testing.T) {
 	err := errors.New("error")
 	const tenantID = "1234"
 	const deviceID = "abcd"
+	const publicKey = "cHVibGljLWtleQ=="
 
 	store := &store_mocks.DataStore{}
 	store.On("ProvisionDevice",
@@ -81,12 +84,13 @@ func TestProvisionDevice(t *testing.T) {
 		}),
 		tenantID,
 		deviceID,
+		publicKey,
 	).Return(err)
 
 	app := NewDeviceConnectApp(store, nil)
 
 	ctx := context.Background()
-	res := app.ProvisionDevice(ctx, tenantID, &model.Device{ID: deviceID})
+	res := app.ProvisionDevice(ctx, tenantID, &model.Device{ID: deviceID, PublicKey: publicKey})
 	assert.Equal(t, err, res)
 
 	store.AssertExpectations(t)
@@ -182,7 +186,74 @@ func TestUpdateDeviceStatus(t *testing.T) {
 	app := NewDeviceConnectApp(store, nil)
 
 	ctx := context.Background()
-	res := app.UpdateDeviceStatus(ctx, tenantID, deviceID, "anything")
+	term, _, electErr := app.mastership.Elect(ctx, mastershipKey(tenantID, deviceID))
+	assert.NoError(t, electErr)
+
+	res := app.UpdateDeviceStatus(ctx, tenantID, deviceID, "anything", term)
+	assert.Equal(t, err, res)
+
+	store.AssertExpectations(t)
+}
+
+func TestUpdateDeviceStatusTermStale(t *testing.T) {
+	const tenantID = "1234"
+	const deviceID = "abcd"
+
+	store := &store_mocks.DataStore{}
+
+	app := NewDeviceConnectApp(store, nil)
+
+	ctx := context.Background()
+	term, lostCh, err := app.mastership.Elect(ctx, mastershipKey(tenantID, deviceID))
+	assert.NoError(t, err)
+
+	res := app.UpdateDeviceStatus(ctx, tenantID, deviceID, "anything", term+1)
+	assert.Equal(t, ErrMastershipTermStale, res)
+
+	select {
+	case <-lostCh:
+		t.Fatal("lease should still be held")
+	default:
+	}
+
+	store.AssertExpectations(t)
+}
+
+func TestUpdateDeviceStatusTermUnknown(t *testing.T) {
+	const tenantID = "1234"
+	const deviceID = "abcd"
+
+	store := &store_mocks.DataStore{}
+
+	app := NewDeviceConnectApp(store, nil)
+
+	ctx := context.Background()
+	res := app.UpdateDeviceStatus(ctx, tenantID, deviceID, "anything", 1)
+	assert.Equal(t, ErrMastershipTermStale, res)
+
+	store.AssertExpectations(t)
+}
+
+func TestRotateDeviceKey(t *testing.T) {
+	err := errors.New("error")
+	const tenantID = "1234"
+	const deviceID = "abcd"
+	const newPub = "bmV3LXB1YmxpYy1rZXk="
+
+	store := &store_mocks.DataStore{}
+	store.On("RotateDeviceKey",
+		mock.MatchedBy(func(ctx context.Context) bool {
+			return true
+		}),
+		tenantID,
+		deviceID,
+		newPub,
+	).Return(err)
+
+	app := NewDeviceConnectApp(store, nil)
+
+	ctx := context.Background()
+	res := app.RotateDeviceKey(ctx, tenantID, deviceID, newPub)
 	assert.Equal(t, err, res)
 
 	store.AssertExpectations(t)
@@ -289,10 +360,89 @@ func TestPrepareUserSession(t *testing.T) {
 	}
 }
 
+func TestPrepareUserSessionDeviceSignature(t *testing.T) {
+	testCases := []struct {
+		name          string
+		challengeErr  error
+		err           error
+		sessionResult *model.Session
+	}{
+		{
+			name:         "signature timeout",
+			challengeErr: ErrDeviceSignatureTimeout,
+			err:          ErrDeviceSignatureTimeout,
+		},
+		{
+			name:         "wrong key",
+			challengeErr: ErrDeviceSignatureInvalid,
+			err:          ErrDeviceSignatureInvalid,
+		},
+		{
+			name: "rotated key verifies",
+			sessionResult: &model.Session{
+				ID:       "id",
+				UserID:   "2",
+				DeviceID: "3",
+				Status:   model.SessionStatusDisconnected,
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			const tenantID = "1"
+			const userID = "2"
+			const deviceID = "3"
+			const publicKey = "cm90YXRlZC1wdWJsaWMta2V5"
+
+			device := &model.Device{
+				ID:        deviceID,
+				Status:    model.DeviceStatusConnected,
+				PublicKey: publicKey,
+			}
+
+			store := &store_mocks.DataStore{}
+			store.On("GetDevice",
+				mock.MatchedBy(func(ctx context.Context) bool { return true }),
+				tenantID,
+				deviceID,
+			).Return(device, nil)
+
+			if tc.challengeErr == nil {
+				store.On("UpsertSession",
+					mock.MatchedBy(func(ctx context.Context) bool { return true }),
+					tenantID,
+					userID,
+					deviceID,
+				).Return(tc.sessionResult, nil)
+			}
+
+			authenticator := &app_mocks.DeviceAuthenticator{}
+			authenticator.On("Challenge",
+				mock.MatchedBy(func(ctx context.Context) bool { return true }),
+				tenantID,
+				deviceID,
+				userID,
+				publicKey,
+			).Return(tc.challengeErr)
+
+			app := NewDeviceConnectApp(store, nil).WithDeviceAuthenticator(authenticator)
+
+			ctx := context.Background()
+			session, err := app.PrepareUserSession(ctx, tenantID, userID, deviceID)
+			assert.Equal(t, tc.sessionResult, session)
+			assert.Equal(t, tc.err, err)
+
+			store.AssertExpectations(t)
+			authenticator.AssertExpectations(t)
+		})
+	}
+}
+
 func TestUpdateUserSessionStatus(t *testing.T) {
 	err := errors.New("error")
 	const tenantID = "1234"
 	const deviceID = "abcd"
+	const sessionID = "session1"
 
 	store := &store_mocks.DataStore{}
 	store.On("UpdateSessionStatus",
@@ -300,24 +450,47 @@ func TestUpdateUserSessionStatus(t *testing.T) {
 			return true
 		}),
 		tenantID,
-		deviceID,
+		sessionID,
 		mock.AnythingOfType("string"),
 	).Return(err)
 
 	app := NewDeviceConnectApp(store, nil)
 
 	ctx := context.Background()
-	res := app.UpdateUserSessionStatus(ctx, tenantID, deviceID, "anything")
+	term, _, electErr := app.mastership.Elect(ctx, mastershipKey(tenantID, deviceID))
+	assert.NoError(t, electErr)
+
+	res := app.UpdateUserSessionStatus(ctx, tenantID, deviceID, sessionID, "anything", term)
 	assert.Equal(t, err, res)
 
 	store.AssertExpectations(t)
 }
 
+func TestUpdateUserSessionStatusTermStale(t *testing.T) {
+	const tenantID = "1234"
+	const deviceID = "abcd"
+	const sessionID = "session1"
+
+	store := &store_mocks.DataStore{}
+
+	app := NewDeviceConnectApp(store, nil)
+
+	ctx := context.Background()
+	term, _, err := app.mastership.Elect(ctx, mastershipKey(tenantID, deviceID))
+	assert.NoError(t, err)
+
+	res := app.UpdateUserSessionStatus(ctx, tenantID, deviceID, sessionID, "anything", term+1)
+	assert.Equal(t, ErrMastershipTermStale, res)
+
+	store.AssertExpectations(t)
+}
+
 func TestPublishMessageFromDevice(t *testing.T) {
 	const tenantID = "abcd"
 	const deviceID = "1234567890"
+	const sessionID = "session1"
 
-	subject := getMessageSubject(tenantID, deviceID, "device")
+	subject := natsClient.SessionSubject(deviceID, sessionID, "device")
 
 	message := &model.Message{
 		Type: model.TypeShell,
@@ -340,15 +513,16 @@ func TestPublishMessageFromDevice(t *testing.T) {
 	app := NewDeviceConnectApp(nil, client)
 
 	ctx := context.Background()
-	err := app.PublishMessageFromDevice(ctx, tenantID, deviceID, message)
+	err := app.PublishMessageFromDevice(ctx, tenantID, deviceID, sessionID, message)
 	assert.NoError(t, err)
 }
 
 func TestPublishMessageFromManagement(t *testing.T) {
 	const tenantID = "abcd"
 	const deviceID = "1234567890"
+	const sessionID = "session1"
 
-	subject := getMessageSubject(tenantID, deviceID, "management")
+	subject := natsClient.SessionSubject(deviceID, sessionID, "mgmt")
 
 	message := &model.Message{
 		Type: model.TypeShell,
@@ -371,15 +545,16 @@ func TestPublishMessageFromManagement(t *testing.T) {
 	app := NewDeviceConnectApp(nil, client)
 
 	ctx := context.Background()
-	err := app.PublishMessageFromManagement(ctx, tenantID, deviceID, message)
+	err := app.PublishMessageFromManagement(ctx, tenantID, deviceID, sessionID, message)
 	assert.NoError(t, err)
 }
 
 func TestSubscribeMessagesFromDevice(t *testing.T) {
 	const tenantID = "abcd"
 	const deviceID = "1234567890"
+	const sessionID = "session1"
 
-	subject := getMessageSubject(tenantID, deviceID, "device")
+	subject := natsClient.SessionSubject(deviceID, sessionID, "device")
 
 	message := &model.Message{
 		Type: model.TypeShell,
@@ -396,12 +571,12 @@ func TestSubscribeMessagesFromDevice(t *testing.T) {
 
 			return true
 		}),
-	).Return(nil)
+	).Return(func() error { return nil }, nil)
 
 	app := NewDeviceConnectApp(nil, client)
 
 	ctx := context.Background()
-	out, err := app.SubscribeMessagesFromDevice(ctx, tenantID, deviceID)
+	out, _, err := app.SubscribeMessagesFromDevice(ctx, tenantID, deviceID, sessionID, SubscribeOptions{})
 	assert.NoError(t, err)
 	assert.NotNil(t, out)
 
@@ -412,8 +587,9 @@ func TestSubscribeMessagesFromDevice(t *testing.T) {
 func TestSubscribeMessagesFromManagement(t *testing.T) {
 	const tenantID = "abcd"
 	const deviceID = "1234567890"
+	const sessionID = "session1"
 
-	subject := getMessageSubject(tenantID, deviceID, "management")
+	subject := natsClient.SessionSubject(deviceID, sessionID, "mgmt")
 
 	message := &model.Message{
 		Type: model.TypeShell,
@@ -430,15 +606,206 @@ func TestSubscribeMessagesFromManagement(t *testing.T) {
 
 			return true
 		}),
-	).Return(nil)
+	).Return(func() error { return nil }, nil)
 
 	app := NewDeviceConnectApp(nil, client)
 
 	ctx := context.Background()
-	out, err := app.SubscribeMessagesFromManagement(ctx, tenantID, deviceID)
+	out, _, err := app.SubscribeMessagesFromManagement(ctx, tenantID, deviceID, sessionID, SubscribeOptions{})
 	assert.NoError(t, err)
 	assert.NotNil(t, out)
 
 	msg := <-out
 	assert.Equal(t, message, msg)
 }
+
+func TestSubscribeMessagesOverflowPolicies(t *testing.T) {
+	const tenantID = "abcd"
+	const deviceID = "1234567890"
+	const sessionID = "session1"
+
+	subject := natsClient.SessionSubject(deviceID, sessionID, "device")
+
+	newMessage := func(data string) *model.Message {
+		return &model.Message{Type: model.TypeShell, Data: []byte(data)}
+	}
+
+	// sendN registers a Subscribe mock that feeds n messages to the
+	// callback synchronously, simulating a NATS consumer that produces
+	// faster than a blocked/slow client can drain, then returns the
+	// callback so later messages can be pushed directly in the test.
+	sendN := func(t *testing.T, client *nats_mocks.ClientInterface, n int) func(msg *nats.Msg) {
+		var callback func(msg *nats.Msg)
+		client.On("Subscribe",
+			subject,
+			mock.MatchedBy(func(cb func(msg *nats.Msg)) bool {
+				callback = cb
+				return true
+			}),
+		).Return(func() error { return nil }, nil)
+
+		app := NewDeviceConnectApp(nil, client)
+		ctx := context.Background()
+		out, events, err := app.SubscribeMessagesFromDevice(ctx, tenantID, deviceID, sessionID, SubscribeOptions{
+			Capacity:       1,
+			OverflowPolicy: OverflowCancel,
+		})
+		assert.NoError(t, err)
+
+		for i := 0; i < n; i++ {
+			data, err := msgpack.Marshal(newMessage("data"))
+			assert.NoError(t, err)
+			callback(&nats.Msg{Data: data})
+		}
+
+		select {
+		case ev, ok := <-events:
+			assert.True(t, ok)
+			assert.Equal(t, EventCanceled, ev.Type)
+			assert.ErrorIs(t, ev.Err, ErrOutOfCapacity)
+		default:
+			t.Fatal("expected a canceled event once capacity was exceeded")
+		}
+
+		_, ok := <-out
+		assert.True(t, ok, "buffered message should still be delivered")
+		_, ok = <-out
+		assert.False(t, ok, "channel should be closed after cancellation")
+
+		return callback
+	}
+
+	t.Run("OverflowCancel closes the subscription once full", func(t *testing.T) {
+		client := &nats_mocks.ClientInterface{}
+		sendN(t, client, 2)
+	})
+
+	t.Run("OverflowDropNewest keeps the buffered message", func(t *testing.T) {
+		client := &nats_mocks.ClientInterface{}
+		var callback func(msg *nats.Msg)
+		client.On("Subscribe",
+			subject,
+			mock.MatchedBy(func(cb func(msg *nats.Msg)) bool {
+				callback = cb
+				return true
+			}),
+		).Return(func() error { return nil }, nil)
+
+		dropped := []*model.Message{}
+		app := NewDeviceConnectApp(nil, client)
+		ctx := context.Background()
+		out, events, err := app.SubscribeMessagesFromDevice(ctx, tenantID, deviceID, sessionID, SubscribeOptions{
+			Capacity:       1,
+			OverflowPolicy: OverflowDropNewest,
+			OnDrop: func(m *model.Message) {
+				dropped = append(dropped, m)
+			},
+		})
+		assert.NoError(t, err)
+
+		first := newMessage("first")
+		second := newMessage("second")
+		for _, m := range []*model.Message{first, second} {
+			data, err := msgpack.Marshal(m)
+			assert.NoError(t, err)
+			callback(&nats.Msg{Data: data})
+		}
+
+		ev := <-events
+		assert.Equal(t, EventSlow, ev.Type)
+
+		msg := <-out
+		assert.Equal(t, first, msg)
+		assert.Equal(t, []*model.Message{second}, dropped)
+	})
+
+	t.Run("OverflowDropOldest drops the oldest buffered message", func(t *testing.T) {
+		client := &nats_mocks.ClientInterface{}
+		var callback func(msg *nats.Msg)
+		client.On("Subscribe",
+			subject,
+			mock.MatchedBy(func(cb func(msg *nats.Msg)) bool {
+				callback = cb
+				return true
+			}),
+		).Return(func() error { return nil }, nil)
+
+		dropped := []*model.Message{}
+		app := NewDeviceConnectApp(nil, client)
+		ctx := context.Background()
+		out, events, err := app.SubscribeMessagesFromDevice(ctx, tenantID, deviceID, sessionID, SubscribeOptions{
+			Capacity:       1,
+			OverflowPolicy: OverflowDropOldest,
+			OnDrop: func(m *model.Message) {
+				dropped = append(dropped, m)
+			},
+		})
+		assert.NoError(t, err)
+
+		first := newMessage("first")
+		second := newMessage("second")
+		for _, m := range []*model.Message{first, second} {
+			data, err := msgpack.Marshal(m)
+			assert.NoError(t, err)
+			callback(&nats.Msg{Data: data})
+		}
+
+		ev := <-events
+		assert.Equal(t, EventSlow, ev.Type)
+
+		msg := <-out
+		assert.Equal(t, second, msg)
+		assert.Equal(t, []*model.Message{first}, dropped)
+	})
+
+	t.Run("OverflowBlock blocks the callback until the consumer drains", func(t *testing.T) {
+		client := &nats_mocks.ClientInterface{}
+		var callback func(msg *nats.Msg)
+		client.On("Subscribe",
+			subject,
+			mock.MatchedBy(func(cb func(msg *nats.Msg)) bool {
+				callback = cb
+				return true
+			}),
+		).Return(func() error { return nil }, nil)
+
+		app := NewDeviceConnectApp(nil, client)
+		ctx := context.Background()
+		out, events, err := app.SubscribeMessagesFromDevice(ctx, tenantID, deviceID, sessionID, SubscribeOptions{
+			Capacity:       1,
+			OverflowPolicy: OverflowBlock,
+		})
+		assert.NoError(t, err)
+
+		first := newMessage("first")
+		second := newMessage("second")
+
+		data, err := msgpack.Marshal(first)
+		assert.NoError(t, err)
+		callback(&nats.Msg{Data: data})
+
+		blocked := make(chan struct{})
+		go func() {
+			data, err := msgpack.Marshal(second)
+			assert.NoError(t, err)
+			callback(&nats.Msg{Data: data})
+			close(blocked)
+		}()
+
+		ev := <-events
+		assert.Equal(t, EventSlow, ev.Type)
+
+		select {
+		case <-blocked:
+			t.Fatal("callback should still be blocked on a full buffer")
+		default:
+		}
+
+		msg := <-out
+		assert.Equal(t, first, msg)
+
+		<-blocked
+		msg = <-out
+		assert.Equal(t, second, msg)
+	})
+}
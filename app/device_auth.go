@@ -0,0 +1,131 @@
+// Copyright 2026 Northern.tech AS
+//
+//    Licensed under the Apache License, Version 2.0 (the "License");
+//    you may not use this file except in compliance with the License.
+//    You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS,
+//    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//    See the License for the specific language governing permissions and
+//    limitations under the License.
+
+package app
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/vmihailenco/msgpack/v5"
+
+	natsClient "github.com/mendersoftware/deviceconnect/client/nats"
+)
+
+// deviceSignatureTimeout bounds how long PrepareUserSession waits for a
+// device to answer a signature challenge before giving up.
+const deviceSignatureTimeout = 5 * time.Second
+
+// DeviceAuthenticator proves that the device publishing on a tenant's
+// device subject is the same physical device that provisioned the given
+// public key, by challenging it with a random nonce and verifying the
+// signature it publishes back - the same device-key idea snapd uses to
+// authenticate a serial assertion. userID binds the challenge to the user
+// requesting the session, so a signature cannot be replayed to approve a
+// session for a different user.
+type DeviceAuthenticator interface {
+	Challenge(ctx context.Context, tenantID string, deviceID string, userID string, publicKey string) error
+}
+
+type challengeRequest struct {
+	Nonce  []byte `msgpack:"nonce"`
+	UserID string `msgpack:"user_id"`
+}
+
+type challengeResponse struct {
+	Signature []byte `msgpack:"signature"`
+}
+
+// natsDeviceAuthenticator is the default DeviceAuthenticator, challenging
+// the device over its NATS subject.
+type natsDeviceAuthenticator struct {
+	client natsClient.ClientInterface
+}
+
+func newNatsDeviceAuthenticator(client natsClient.ClientInterface) *natsDeviceAuthenticator {
+	return &natsDeviceAuthenticator{client: client}
+}
+
+func challengeSubject(tenantID string, deviceID string) string {
+	return fmt.Sprintf("device.%s.%s.auth", tenantID, deviceID)
+}
+
+// Challenge sends a random nonce on the device's auth subject as a
+// request-reply call and waits up to deviceSignatureTimeout for the
+// device to reply with a signature over
+// sha256(nonce || tenantID || deviceID || userID), verified against
+// publicKey. Using Request rather than a standing Subscribe means NATS
+// itself tears down the reply inbox once this call returns, so nothing is
+// leaked by a device that never answers.
+func (d *natsDeviceAuthenticator) Challenge(
+	ctx context.Context, tenantID string, deviceID string, userID string, publicKey string,
+) error {
+	pub, err := decodeEd25519PublicKey(publicKey)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrDeviceSignatureInvalid, err)
+	}
+
+	nonce := make([]byte, 32)
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+
+	req, err := msgpack.Marshal(&challengeRequest{Nonce: nonce, UserID: userID})
+	if err != nil {
+		return err
+	}
+
+	timeout := deviceSignatureTimeout
+	if deadline, ok := ctx.Deadline(); ok {
+		if remaining := time.Until(deadline); remaining < timeout {
+			timeout = remaining
+		}
+	}
+
+	msg, err := d.client.Request(challengeSubject(tenantID, deviceID), req, timeout)
+	if err != nil {
+		if errors.Is(err, nats.ErrTimeout) {
+			return ErrDeviceSignatureTimeout
+		}
+		return err
+	}
+
+	resp := &challengeResponse{}
+	if err := msgpack.Unmarshal(msg.Data, resp); err != nil {
+		return fmt.Errorf("%w: %s", ErrDeviceSignatureInvalid, err)
+	}
+	digest := sha256.Sum256(append(append([]byte{}, nonce...), []byte(tenantID+deviceID+userID)...))
+	if !ed25519.Verify(pub, digest[:], resp.Signature) {
+		return ErrDeviceSignatureInvalid
+	}
+	return nil
+}
+
+func decodeEd25519PublicKey(encoded string) (ed25519.PublicKey, error) {
+	raw, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, err
+	}
+	if len(raw) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("invalid ed25519 public key length: %d", len(raw))
+	}
+	return ed25519.PublicKey(raw), nil
+}
@@ -0,0 +1,148 @@
+// Copyright 2026 Northern.tech AS
+//
+//    Licensed under the Apache License, Version 2.0 (the "License");
+//    you may not use this file except in compliance with the License.
+//    You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS,
+//    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//    See the License for the specific language governing permissions and
+//    limitations under the License.
+
+package app
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/vmihailenco/msgpack/v5"
+
+	nats_mocks "github.com/mendersoftware/deviceconnect/client/nats/mocks"
+)
+
+// replyWithSignature registers a Request mock that decodes the
+// challengeRequest it is sent, signs sha256(nonce || tenantID || deviceID
+// || userID) with priv, and replies with the resulting challengeResponse
+// - simulating a real device answering the challenge.
+func replyWithSignature(
+	client *nats_mocks.ClientInterface, subject string, tenantID string, deviceID string, priv ed25519.PrivateKey,
+) {
+	client.On("Request",
+		subject,
+		mock.MatchedBy(func(data []byte) bool {
+			return true
+		}),
+		mock.AnythingOfType("time.Duration"),
+	).Return(func(_ string, data []byte, _ time.Duration) *nats.Msg {
+		req := &challengeRequest{}
+		_ = msgpack.Unmarshal(data, req)
+
+		digest := sha256.Sum256(append(append([]byte{}, req.Nonce...), []byte(tenantID+deviceID+req.UserID)...))
+		signature := ed25519.Sign(priv, digest[:])
+
+		respData, _ := msgpack.Marshal(&challengeResponse{Signature: signature})
+		return &nats.Msg{Data: respData}
+	}, nil)
+}
+
+func TestChallengeValidSignature(t *testing.T) {
+	const tenantID = "1234"
+	const deviceID = "abcd"
+	const userID = "user1"
+
+	pub, priv, err := ed25519.GenerateKey(nil)
+	assert.NoError(t, err)
+
+	client := &nats_mocks.ClientInterface{}
+	replyWithSignature(client, challengeSubject(tenantID, deviceID), tenantID, deviceID, priv)
+
+	authenticator := newNatsDeviceAuthenticator(client)
+	res := authenticator.Challenge(
+		context.Background(), tenantID, deviceID, userID, base64.StdEncoding.EncodeToString(pub),
+	)
+	assert.NoError(t, res)
+
+	client.AssertExpectations(t)
+}
+
+func TestChallengeWrongKey(t *testing.T) {
+	const tenantID = "1234"
+	const deviceID = "abcd"
+	const userID = "user1"
+
+	_, priv, err := ed25519.GenerateKey(nil)
+	assert.NoError(t, err)
+	otherPub, _, err := ed25519.GenerateKey(nil)
+	assert.NoError(t, err)
+
+	client := &nats_mocks.ClientInterface{}
+	replyWithSignature(client, challengeSubject(tenantID, deviceID), tenantID, deviceID, priv)
+
+	authenticator := newNatsDeviceAuthenticator(client)
+	res := authenticator.Challenge(
+		context.Background(), tenantID, deviceID, userID, base64.StdEncoding.EncodeToString(otherPub),
+	)
+	assert.ErrorIs(t, res, ErrDeviceSignatureInvalid)
+
+	client.AssertExpectations(t)
+}
+
+func TestChallengeTimeout(t *testing.T) {
+	const tenantID = "1234"
+	const deviceID = "abcd"
+	const userID = "user1"
+
+	pub, _, err := ed25519.GenerateKey(nil)
+	assert.NoError(t, err)
+
+	client := &nats_mocks.ClientInterface{}
+	client.On("Request",
+		challengeSubject(tenantID, deviceID),
+		mock.AnythingOfType("[]uint8"),
+		mock.AnythingOfType("time.Duration"),
+	).Return(nil, nats.ErrTimeout)
+
+	authenticator := newNatsDeviceAuthenticator(client)
+	res := authenticator.Challenge(
+		context.Background(), tenantID, deviceID, userID, base64.StdEncoding.EncodeToString(pub),
+	)
+	assert.ErrorIs(t, res, ErrDeviceSignatureTimeout)
+
+	client.AssertExpectations(t)
+}
+
+func TestDecodeEd25519PublicKeyErrors(t *testing.T) {
+	t.Run("invalid base64", func(t *testing.T) {
+		_, err := decodeEd25519PublicKey("not-valid-base64!!")
+		assert.Error(t, err)
+	})
+
+	t.Run("wrong length", func(t *testing.T) {
+		_, err := decodeEd25519PublicKey(base64.StdEncoding.EncodeToString([]byte("too short")))
+		assert.Error(t, err)
+	})
+}
+
+func TestChallengeInvalidPublicKey(t *testing.T) {
+	const tenantID = "1234"
+	const deviceID = "abcd"
+	const userID = "user1"
+
+	client := &nats_mocks.ClientInterface{}
+
+	authenticator := newNatsDeviceAuthenticator(client)
+	res := authenticator.Challenge(context.Background(), tenantID, deviceID, userID, "not-valid-base64!!")
+	assert.ErrorIs(t, res, ErrDeviceSignatureInvalid)
+
+	client.AssertExpectations(t)
+}
@@ -0,0 +1,47 @@
+// Copyright 2020 Northern.tech AS
+//
+//    Licensed under the Apache License, Version 2.0 (the "License");
+//    you may not use this file except in compliance with the License.
+//    You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS,
+//    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//    See the License for the specific language governing permissions and
+//    limitations under the License.
+
+package app
+
+import "errors"
+
+var (
+	// ErrDeviceNotFound is returned when a device with the given ID does
+	// not exist for the tenant.
+	ErrDeviceNotFound = errors.New("device not found")
+	// ErrDeviceNotConnected is returned when a session is requested
+	// against a device that is not currently connected.
+	ErrDeviceNotConnected = errors.New("device not connected")
+	// ErrSessionNotFound is returned when resuming a session that does
+	// not exist for the tenant.
+	ErrSessionNotFound = errors.New("session not found")
+	// ErrJetStreamNotConfigured is returned by ResumeUserSession when
+	// the app was constructed with a core-NATS client instead of a
+	// JetStreamClientInterface, since resuming a session requires
+	// persisted, replayable messages.
+	ErrJetStreamNotConfigured = errors.New("jetstream is not configured")
+	// ErrDeviceSignatureInvalid is returned when a device fails to
+	// prove, via the PrepareUserSession challenge/response, that it
+	// holds the private key matching its provisioned public key.
+	ErrDeviceSignatureInvalid = errors.New("device signature is invalid")
+	// ErrDeviceSignatureTimeout is returned when a device does not
+	// answer a PrepareUserSession signature challenge in time.
+	ErrDeviceSignatureTimeout = errors.New("timed out waiting for device signature")
+	// ErrMastershipTermStale is returned by a write guarded by a
+	// mastership term when CurrentTerm reports a different term for the
+	// same key, i.e. some other replica has taken over the device, or
+	// reports no term at all, i.e. the lease has expired or was never
+	// granted.
+	ErrMastershipTermStale = errors.New("mastership term is stale")
+)
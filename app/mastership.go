@@ -0,0 +1,242 @@
+// Copyright 2026 Northern.tech AS
+//
+//    Licensed under the Apache License, Version 2.0 (the "License");
+//    you may not use this file except in compliance with the License.
+//    You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS,
+//    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//    See the License for the specific language governing permissions and
+//    limitations under the License.
+
+package app
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+const (
+	mastershipBackoffMin = 250 * time.Millisecond
+	mastershipBackoffMax = 30 * time.Second
+)
+
+// Mastership elects exactly one owner, across all deviceconnect replicas,
+// for a given key - normally mastershipKey(tenantID, deviceID). Only the
+// elected replica is allowed to hold the NATS subscription and write
+// device/session status for that key; every other replica must forward
+// management-side traffic to it instead of acting on it directly.
+type Mastership interface {
+	// Elect blocks until this replica becomes master for key or ctx is
+	// canceled. On success it returns the term the caller now holds and
+	// a channel that is closed the instant the lease is lost, so the
+	// caller knows to stop touching the device's state and tear down
+	// whatever it started under that term.
+	Elect(ctx context.Context, key string) (term uint64, lostCh <-chan struct{}, err error)
+	// CurrentTerm returns the term currently recorded for key, if any.
+	// Callers compare this against the term they were granted by Elect
+	// before performing a write, so that a replica whose lease has
+	// already moved on cannot race a write in behind the new master.
+	CurrentTerm(key string) (term uint64, ok bool)
+}
+
+func mastershipKey(tenantID, deviceID string) string {
+	return fmt.Sprintf("mastership/%s/%s", tenantID, deviceID)
+}
+
+// singleReplicaMastership is the Mastership used when deviceconnect has
+// not been configured with a distributed backend: with only one replica
+// running, it is master for every key by definition and never loses a
+// lease until every caller holding it has had its context canceled.
+//
+// A key is commonly held concurrently by more than one caller on the same
+// replica - e.g. a device's management-side and device-side subscriptions
+// are elected independently for the same mastershipKey - so Elect is
+// refcounted per key: the first Elect for a key mints a new term and
+// lease, every subsequent Elect for the same still-held key just joins it,
+// and the lease (and its lostCh) is only torn down once every holder's
+// context has ended.
+type singleReplicaMastership struct {
+	mu     sync.Mutex
+	terms  map[string]uint64
+	leases map[string]*singleReplicaLease
+}
+
+type singleReplicaLease struct {
+	term   uint64
+	refs   int
+	lostCh chan struct{}
+}
+
+func newSingleReplicaMastership() *singleReplicaMastership {
+	return &singleReplicaMastership{
+		terms:  make(map[string]uint64),
+		leases: make(map[string]*singleReplicaLease),
+	}
+}
+
+func (m *singleReplicaMastership) Elect(
+	ctx context.Context, key string,
+) (uint64, <-chan struct{}, error) {
+	m.mu.Lock()
+	lease, ok := m.leases[key]
+	if !ok {
+		m.terms[key]++
+		lease = &singleReplicaLease{term: m.terms[key], lostCh: make(chan struct{})}
+		m.leases[key] = lease
+	}
+	lease.refs++
+	m.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		m.release(key, lease)
+	}()
+	return lease.term, lease.lostCh, nil
+}
+
+func (m *singleReplicaMastership) release(key string, lease *singleReplicaLease) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	lease.refs--
+	if lease.refs > 0 {
+		return
+	}
+	delete(m.leases, key)
+	close(lease.lostCh)
+}
+
+func (m *singleReplicaMastership) CurrentTerm(key string) (uint64, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	lease, ok := m.leases[key]
+	if !ok {
+		return 0, false
+	}
+	return lease.term, true
+}
+
+// NatsMastership elects a master per key using a NATS JetStream KV bucket
+// as a distributed lock: the term is a random value fixed for the life of
+// the lease and stored as the entry's value, while the KV revision (which
+// changes on every renewal) is used only internally to CAS the next
+// update. A replica keeps its lease alive by refreshing the entry before
+// the bucket's per-key TTL expires.
+type NatsMastership struct {
+	kv       nats.KeyValue
+	leaseTTL time.Duration
+}
+
+// NewNatsMastership opens (creating if necessary) the JetStream KV bucket
+// used to track mastership, with entries expiring after leaseTTL unless
+// renewed.
+func NewNatsMastership(
+	js nats.JetStreamContext, bucket string, leaseTTL time.Duration,
+) (*NatsMastership, error) {
+	kv, err := js.KeyValue(bucket)
+	if err == nats.ErrBucketNotFound {
+		kv, err = js.CreateKeyValue(&nats.KeyValueConfig{
+			Bucket: bucket,
+			TTL:    leaseTTL,
+		})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("mastership: opening KV bucket %q: %w", bucket, err)
+	}
+	return &NatsMastership{kv: kv, leaseTTL: leaseTTL}, nil
+}
+
+func (m *NatsMastership) Elect(
+	ctx context.Context, key string,
+) (uint64, <-chan struct{}, error) {
+	backoff := mastershipBackoffMin
+	for {
+		// term identifies this lease and is fixed for its entire
+		// lifetime; it must not be confused with the KV revision, which
+		// advances on every renewal below and would otherwise make
+		// CurrentTerm disagree with what Elect just handed out.
+		term := rand.Uint64()
+		if revision, err := m.kv.Create(key, encodeTerm(term)); err == nil {
+			lostCh := make(chan struct{})
+			go m.renew(ctx, key, term, revision, lostCh)
+			return term, lostCh, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return 0, nil, ctx.Err()
+		case <-time.After(fullJitter(backoff)):
+		}
+		if backoff *= 2; backoff > mastershipBackoffMax {
+			backoff = mastershipBackoffMax
+		}
+	}
+}
+
+// renew keeps refreshing the lease until ctx is canceled or the update is
+// rejected because another replica has already taken over the key (its
+// revision no longer matches revision), at which point lostCh is closed.
+// The value written back on every renewal is always encodeTerm(term): the
+// term itself never changes across renewals, only the revision used to
+// CAS the next update.
+func (m *NatsMastership) renew(
+	ctx context.Context, key string, term uint64, revision uint64, lostCh chan struct{},
+) {
+	defer close(lostCh)
+
+	ticker := time.NewTicker(m.leaseTTL / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			_ = m.kv.Delete(key)
+			return
+		case <-ticker.C:
+			newRevision, err := m.kv.Update(key, encodeTerm(term), revision)
+			if err != nil {
+				return
+			}
+			revision = newRevision
+		}
+	}
+}
+
+func (m *NatsMastership) CurrentTerm(key string) (uint64, bool) {
+	entry, err := m.kv.Get(key)
+	if err != nil {
+		return 0, false
+	}
+	return decodeTerm(entry.Value()), true
+}
+
+func encodeTerm(v uint64) []byte {
+	buf := make([]byte, 8)
+	binary.BigEndian.PutUint64(buf, v)
+	return buf
+}
+
+func decodeTerm(buf []byte) uint64 {
+	if len(buf) != 8 {
+		return 0
+	}
+	return binary.BigEndian.Uint64(buf)
+}
+
+// fullJitter returns a random duration in [0, d), per the "full jitter"
+// backoff strategy, so that replicas retrying a lost election do not
+// thrash the same key in lockstep.
+func fullJitter(d time.Duration) time.Duration {
+	if d <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(d)))
+}
@@ -0,0 +1,102 @@
+// Copyright 2026 Northern.tech AS
+//
+//    Licensed under the Apache License, Version 2.0 (the "License");
+//    you may not use this file except in compliance with the License.
+//    You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS,
+//    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//    See the License for the specific language governing permissions and
+//    limitations under the License.
+
+package app
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSingleReplicaMastershipSharesTermForConcurrentHolders(t *testing.T) {
+	m := newSingleReplicaMastership()
+	ctx1, cancel1 := context.WithCancel(context.Background())
+	ctx2, cancel2 := context.WithCancel(context.Background())
+	defer cancel1()
+	defer cancel2()
+
+	term1, lostCh1, err := m.Elect(ctx1, "key")
+	assert.NoError(t, err)
+	term2, lostCh2, err := m.Elect(ctx2, "key")
+	assert.NoError(t, err)
+
+	assert.Equal(t, term1, term2, "two holders of the same key must share a term")
+	current, ok := m.CurrentTerm("key")
+	assert.True(t, ok)
+	assert.Equal(t, term1, current)
+
+	// Releasing only one of the two holders must not end the lease: the
+	// other holder is still relying on lostCh never having fired and on
+	// CurrentTerm still reporting its term as current.
+	cancel1()
+	assertNotClosed(t, lostCh1)
+	current, ok = m.CurrentTerm("key")
+	assert.True(t, ok)
+	assert.Equal(t, term1, current)
+
+	cancel2()
+	assertEventuallyClosed(t, lostCh2)
+}
+
+func TestSingleReplicaMastershipNewTermAfterFullRelease(t *testing.T) {
+	m := newSingleReplicaMastership()
+	ctx, cancel := context.WithCancel(context.Background())
+
+	term1, lostCh, err := m.Elect(ctx, "key")
+	assert.NoError(t, err)
+	cancel()
+	assertEventuallyClosed(t, lostCh)
+
+	_, ok := m.CurrentTerm("key")
+	assert.False(t, ok, "lease must be gone once its last holder releases it")
+
+	term2, _, err := m.Elect(context.Background(), "key")
+	assert.NoError(t, err)
+	assert.NotEqual(t, term1, term2, "a fresh election must mint a new term")
+}
+
+func TestSingleReplicaMastershipCurrentTermUnknownKey(t *testing.T) {
+	m := newSingleReplicaMastership()
+	_, ok := m.CurrentTerm("never-elected")
+	assert.False(t, ok)
+}
+
+func TestFullJitter(t *testing.T) {
+	assert.Equal(t, time.Duration(0), fullJitter(0))
+	for i := 0; i < 100; i++ {
+		d := fullJitter(time.Second)
+		assert.True(t, d >= 0 && d < time.Second)
+	}
+}
+
+func assertNotClosed(t *testing.T, ch <-chan struct{}) {
+	t.Helper()
+	select {
+	case <-ch:
+		t.Fatal("channel closed unexpectedly")
+	case <-time.After(10 * time.Millisecond):
+	}
+}
+
+func assertEventuallyClosed(t *testing.T, ch <-chan struct{}) {
+	t.Helper()
+	select {
+	case <-ch:
+	case <-time.After(time.Second):
+		t.Fatal("channel was not closed in time")
+	}
+}
@@ -0,0 +1,55 @@
+// Copyright 2026 Northern.tech AS
+//
+//    Licensed under the Apache License, Version 2.0 (the "License");
+//    you may not use this file except in compliance with the License.
+//    You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS,
+//    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//    See the License for the specific language governing permissions and
+//    limitations under the License.
+
+package app
+
+import "github.com/prometheus/client_golang/prometheus"
+
+var (
+	subscriptionQueueDepth = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "deviceconnect",
+			Subsystem: "subscription",
+			Name:      "queue_depth",
+			Help:      "Number of messages buffered in a tenant's subscription channel right after its most recent enqueue.",
+		},
+		[]string{"tenant_id"},
+	)
+	subscriptionDropsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "deviceconnect",
+			Subsystem: "subscription",
+			Name:      "drops_total",
+			Help:      "Number of messages dropped from a subscription channel, by overflow policy.",
+		},
+		[]string{"tenant_id", "policy"},
+	)
+	subscriptionCancelsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "deviceconnect",
+			Subsystem: "subscription",
+			Name:      "cancels_total",
+			Help:      "Number of subscriptions canceled because their consumer ran out of capacity.",
+		},
+		[]string{"tenant_id"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(
+		subscriptionQueueDepth,
+		subscriptionDropsTotal,
+		subscriptionCancelsTotal,
+	)
+}
@@ -0,0 +1,159 @@
+// Copyright 2026 Northern.tech AS
+//
+//    Licensed under the Apache License, Version 2.0 (the "License");
+//    you may not use this file except in compliance with the License.
+//    You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS,
+//    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//    See the License for the specific language governing permissions and
+//    limitations under the License.
+
+package app
+
+import (
+	"errors"
+
+	"github.com/mendersoftware/deviceconnect/model"
+)
+
+// defaultSubscriptionCapacity is used when SubscribeOptions.Capacity is
+// not set (<= 0).
+const defaultSubscriptionCapacity = 64
+
+// OverflowPolicy controls what happens to a Subscribe...'s message
+// channel when the consumer falls behind the NATS callback feeding it.
+type OverflowPolicy int
+
+const (
+	// OverflowBlock blocks the NATS callback until the consumer catches
+	// up. This is the safest policy but a stalled consumer stalls
+	// delivery for the whole subject.
+	OverflowBlock OverflowPolicy = iota
+	// OverflowDropOldest discards the oldest buffered message to make
+	// room for the new one.
+	OverflowDropOldest
+	// OverflowDropNewest discards the incoming message, keeping
+	// whatever is already buffered.
+	OverflowDropNewest
+	// OverflowCancel cancels the subscription the first time the buffer
+	// is found full, emitting ErrOutOfCapacity on the event channel.
+	OverflowCancel
+)
+
+// ErrOutOfCapacity is the error carried by a Canceled SubscriptionEvent
+// when OverflowCancel canceled a subscription because its consumer could
+// not keep up.
+var ErrOutOfCapacity = errors.New("subscription canceled: consumer out of capacity")
+
+// SubscriptionEventType identifies the kind of SubscriptionEvent.
+type SubscriptionEventType int
+
+const (
+	// EventSlow is emitted when a message had to be dropped, or blocked
+	// on, because the consumer was not keeping up. It is not delivered
+	// while an earlier event is still unread on the event channel.
+	EventSlow SubscriptionEventType = iota
+	// EventCanceled is emitted once, immediately before the message
+	// channel is closed because of OverflowCancel.
+	EventCanceled
+)
+
+// SubscriptionEvent reports backpressure conditions on a subscription's
+// message channel so the caller can decide whether to close the
+// WebSocket it is driving.
+type SubscriptionEvent struct {
+	Type SubscriptionEventType
+	Err  error
+}
+
+// SubscribeOptions configures the buffering and overflow behavior of a
+// Subscribe... call.
+type SubscribeOptions struct {
+	// Capacity of the returned message channel. Defaults to
+	// defaultSubscriptionCapacity when <= 0.
+	Capacity int
+	// OverflowPolicy decides what happens once Capacity is exhausted.
+	OverflowPolicy OverflowPolicy
+	// OnDrop, if set, is called synchronously with every message
+	// discarded by OverflowDropOldest or OverflowDropNewest.
+	OnDrop func(*model.Message)
+}
+
+func (o SubscribeOptions) capacity() int {
+	if o.Capacity <= 0 {
+		return defaultSubscriptionCapacity
+	}
+	return o.Capacity
+}
+
+// enqueue delivers msg to out according to opts, reporting backpressure
+// on events. It returns false once the subscription should be torn down
+// (OverflowCancel ran out of capacity).
+func enqueue(
+	tenantID string,
+	out chan *model.Message,
+	events chan SubscriptionEvent,
+	opts SubscribeOptions,
+	msg *model.Message,
+) bool {
+	select {
+	case out <- msg:
+		subscriptionQueueDepth.WithLabelValues(tenantID).Set(float64(len(out)))
+		return true
+	default:
+	}
+
+	switch opts.OverflowPolicy {
+	case OverflowDropNewest:
+		drop(tenantID, opts, msg, "drop_newest")
+		emitEvent(events, SubscriptionEvent{Type: EventSlow})
+		return true
+
+	case OverflowDropOldest:
+		select {
+		case old := <-out:
+			drop(tenantID, opts, old, "drop_oldest")
+		default:
+		}
+		select {
+		case out <- msg:
+		default:
+			// Another sender refilled out after the drain above; block
+			// until there is room.
+			out <- msg
+		}
+		emitEvent(events, SubscriptionEvent{Type: EventSlow})
+		return true
+
+	case OverflowCancel:
+		subscriptionCancelsTotal.WithLabelValues(tenantID).Inc()
+		emitEvent(events, SubscriptionEvent{Type: EventCanceled, Err: ErrOutOfCapacity})
+		return false
+
+	default: // OverflowBlock
+		emitEvent(events, SubscriptionEvent{Type: EventSlow})
+		out <- msg
+		subscriptionQueueDepth.WithLabelValues(tenantID).Set(float64(len(out)))
+		return true
+	}
+}
+
+func drop(tenantID string, opts SubscribeOptions, msg *model.Message, policy string) {
+	subscriptionDropsTotal.WithLabelValues(tenantID, policy).Inc()
+	if opts.OnDrop != nil {
+		opts.OnDrop(msg)
+	}
+}
+
+// emitEvent sends ev on events without blocking message delivery if no
+// one is currently reading the event channel.
+func emitEvent(events chan SubscriptionEvent, ev SubscriptionEvent) {
+	select {
+	case events <- ev:
+	default:
+	}
+}
@@ -0,0 +1,75 @@
+// Copyright 2020 Northern.tech AS
+//
+//    Licensed under the Apache License, Version 2.0 (the "License");
+//    you may not use this file except in compliance with the License.
+//    You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS,
+//    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//    See the License for the specific language governing permissions and
+//    limitations under the License.
+
+package nats
+
+import (
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+// ClientInterface is a wrapper around the nats.go Conn used throughout
+// deviceconnect so it can be mocked in tests.
+//
+//go:generate ../../utils/mockgen.sh
+type ClientInterface interface {
+	Publish(subject string, data []byte) error
+	// Subscribe delivers messages published on subject to cb until the
+	// returned unsubscribe func is called; the caller must call it once
+	// it is done consuming cb, or the subscription outlives it.
+	Subscribe(subject string, cb nats.MsgHandler) (unsubscribe func() error, err error)
+	// Request publishes data on subject and waits up to timeout for a
+	// single reply, using a core NATS request-reply inbox that NATS
+	// tears down itself once the reply arrives or the request times
+	// out - unlike Subscribe, it leaves nothing behind for the caller
+	// to clean up.
+	Request(subject string, data []byte, timeout time.Duration) (*nats.Msg, error)
+	Close()
+}
+
+// Client is the default ClientInterface implementation backed by a core
+// NATS connection.
+type Client struct {
+	conn *nats.Conn
+}
+
+// NewClient creates a Client connected to the given NATS URL.
+func NewClient(url string) (*Client, error) {
+	conn, err := nats.Connect(url)
+	if err != nil {
+		return nil, err
+	}
+	return &Client{conn: conn}, nil
+}
+
+func (c *Client) Publish(subject string, data []byte) error {
+	return c.conn.Publish(subject, data)
+}
+
+func (c *Client) Subscribe(subject string, cb nats.MsgHandler) (func() error, error) {
+	sub, err := c.conn.Subscribe(subject, cb)
+	if err != nil {
+		return nil, err
+	}
+	return sub.Unsubscribe, nil
+}
+
+func (c *Client) Request(subject string, data []byte, timeout time.Duration) (*nats.Msg, error) {
+	return c.conn.Request(subject, data, timeout)
+}
+
+func (c *Client) Close() {
+	c.conn.Close()
+}
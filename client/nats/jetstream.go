@@ -0,0 +1,169 @@
+// Copyright 2026 Northern.tech AS
+//
+//    Licensed under the Apache License, Version 2.0 (the "License");
+//    you may not use this file except in compliance with the License.
+//    You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS,
+//    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//    See the License for the specific language governing permissions and
+//    limitations under the License.
+
+package nats
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+// streamMaxAge bounds how long a tenant's session messages are retained
+// on the stream, so a client that waits longer than this to resume a
+// session has nothing left to replay.
+const streamMaxAge = 7 * 24 * time.Hour
+
+// Mode names the messaging backends for device<->management traffic:
+// legacy core-NATS delivery or persistent, at-least-once JetStream
+// delivery. Nothing in this package or in the app reads a Mode; the app
+// uses JetStream delivery whenever the client it was constructed with
+// implements JetStreamClientInterface.
+type Mode string
+
+const (
+	// ModeCore is the legacy, fire-and-forget core NATS pub/sub mode.
+	ModeCore Mode = "core"
+	// ModeJetStream persists session messages on a per-tenant JetStream
+	// stream so a reconnecting client can resume a session instead of
+	// losing whatever was published while it was away.
+	ModeJetStream Mode = "jetstream"
+)
+
+// JetStreamClientInterface is the superset of ClientInterface used for
+// persistent, at-least-once message delivery: Publish is acknowledged by
+// the stream, PublishSeq surfaces the assigned sequence number, and
+// Resume lets a caller replay everything published after a given
+// sequence.
+type JetStreamClientInterface interface {
+	ClientInterface
+
+	PublishSeq(subject string, data []byte) (seq uint64, err error)
+	Resume(
+		ctx context.Context, subject string, lastSeq uint64, cb nats.MsgHandler,
+	) (stop func() error, err error)
+}
+
+// StreamName returns the per-tenant JetStream stream session messages
+// for tenantID are recorded on.
+func StreamName(tenantID string) string {
+	return fmt.Sprintf("DEVCONNECT_%s", tenantID)
+}
+
+// SessionSubject returns the subject a given side ("device" or "mgmt")
+// of a session publishes and resumes on.
+func SessionSubject(deviceID string, sessionID string, side string) string {
+	return fmt.Sprintf("session.%s.%s.%s", deviceID, sessionID, side)
+}
+
+// JetStreamClient is a ClientInterface implementation backed by a
+// limits-retention JetStream stream, giving device<->management session
+// messaging at-least-once delivery across reconnects. Limits retention
+// (rather than work-queue) is required here because a session's messages
+// have two independent readers - the live Subscribe consumer and a
+// reconnecting caller's Resume consumer - and work-queue streams both
+// forbid any consumer that isn't DeliverAll and delete a message as soon
+// as any one consumer acks it, which would make Resume unable to replay
+// anything the live consumer already saw.
+type JetStreamClient struct {
+	conn *nats.Conn
+	js   nats.JetStreamContext
+}
+
+// NewJetStreamClient connects to url and returns a client for publishing
+// and resuming session streams on it.
+func NewJetStreamClient(url string) (*JetStreamClient, error) {
+	conn, err := nats.Connect(url)
+	if err != nil {
+		return nil, err
+	}
+	js, err := conn.JetStream()
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return &JetStreamClient{conn: conn, js: js}, nil
+}
+
+// EnsureStream creates the stream for tenantID if it does not already
+// exist.
+func (c *JetStreamClient) EnsureStream(tenantID string) error {
+	name := StreamName(tenantID)
+	if _, err := c.js.StreamInfo(name); err == nil {
+		return nil
+	}
+	_, err := c.js.AddStream(&nats.StreamConfig{
+		Name:      name,
+		Subjects:  []string{"session.*.*.device", "session.*.*.mgmt"},
+		Retention: nats.LimitsPolicy,
+		MaxAge:    streamMaxAge,
+	})
+	return err
+}
+
+func (c *JetStreamClient) Publish(subject string, data []byte) error {
+	_, err := c.js.Publish(subject, data)
+	return err
+}
+
+// PublishSeq publishes data on subject and returns the sequence number
+// JetStream assigned it, for the caller to stamp onto model.Message.Seq.
+func (c *JetStreamClient) PublishSeq(subject string, data []byte) (uint64, error) {
+	ack, err := c.js.Publish(subject, data)
+	if err != nil {
+		return 0, err
+	}
+	return ack.Sequence, nil
+}
+
+// Subscribe delivers only messages published from now on, mirroring core
+// NATS's fire-and-forget semantics; messages published while the caller
+// was away are left on the stream for a later Resume to replay.
+func (c *JetStreamClient) Subscribe(subject string, cb nats.MsgHandler) (func() error, error) {
+	sub, err := c.js.Subscribe(subject, cb, nats.DeliverNew(), nats.AckNone())
+	if err != nil {
+		return nil, err
+	}
+	return sub.Unsubscribe, nil
+}
+
+// Resume creates an ephemeral consumer starting at lastSeq+1, so a
+// reconnecting caller replays any messages it missed and keeps receiving
+// everything published afterwards, delivered to cb. The returned func
+// stops the consumer. nats.StartSequence implies DeliverByStartSequence,
+// so it does not need to be requested explicitly.
+func (c *JetStreamClient) Resume(
+	ctx context.Context, subject string, lastSeq uint64, cb nats.MsgHandler,
+) (func() error, error) {
+	sub, err := c.js.Subscribe(subject, cb,
+		nats.StartSequence(lastSeq+1),
+		nats.AckExplicit(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return func() error {
+		return sub.Unsubscribe()
+	}, nil
+}
+
+func (c *JetStreamClient) Request(subject string, data []byte, timeout time.Duration) (*nats.Msg, error) {
+	return c.conn.Request(subject, data, timeout)
+}
+
+func (c *JetStreamClient) Close() {
+	c.conn.Close()
+}
@@ -0,0 +1,75 @@
+// Copyright 2026 Northern.tech AS
+//
+//    Licensed under the Apache License, Version 2.0 (the "License");
+//    you may not use this file except in compliance with the License.
+//    You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS,
+//    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//    See the License for the specific language governing permissions and
+//    limitations under the License.
+
+//go:build integration
+// +build integration
+
+package nats
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	natsserver "github.com/nats-io/nats-server/v2/server"
+	natstest "github.com/nats-io/nats-server/v2/test"
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func startJetStreamServer(t *testing.T) *natsserver.Server {
+	opts := natstest.DefaultTestOptions
+	opts.Port = -1
+	opts.JetStream = true
+	opts.StoreDir = t.TempDir()
+
+	srv := natstest.RunServer(&opts)
+	t.Cleanup(srv.Shutdown)
+	return srv
+}
+
+func TestJetStreamClientResume(t *testing.T) {
+	srv := startJetStreamServer(t)
+
+	const tenantID = "acme"
+	const deviceID = "device-1"
+	const sessionID = "session-1"
+
+	client, err := NewJetStreamClient(srv.ClientURL())
+	require.NoError(t, err)
+	t.Cleanup(client.Close)
+	require.NoError(t, client.EnsureStream(tenantID))
+
+	subject := SessionSubject(deviceID, sessionID, "device")
+
+	firstSeq, err := client.PublishSeq(subject, []byte("first"))
+	require.NoError(t, err)
+	_, err = client.PublishSeq(subject, []byte("second"))
+	require.NoError(t, err)
+
+	received := make(chan *nats.Msg, 2)
+	stop, err := client.Resume(context.Background(), subject, firstSeq, func(msg *nats.Msg) {
+		received <- msg
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = stop() })
+
+	select {
+	case msg := <-received:
+		assert.Equal(t, "second", string(msg.Data))
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for resumed message")
+	}
+}
@@ -0,0 +1,36 @@
+// Copyright 2020 Northern.tech AS
+//
+//    Licensed under the Apache License, Version 2.0 (the "License");
+//    you may not use this file except in compliance with the License.
+//    You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS,
+//    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//    See the License for the specific language governing permissions and
+//    limitations under the License.
+
+package model
+
+import "time"
+
+const (
+	DeviceStatusConnected    = "connected"
+	DeviceStatusDisconnected = "disconnected"
+)
+
+// Device represents a device known to deviceconnect.
+type Device struct {
+	ID        string    `json:"id" bson:"_id"`
+	Status    string    `json:"status" bson:"status"`
+	CreatedTs time.Time `json:"created_ts" bson:"created_ts"`
+	UpdatedTs time.Time `json:"updated_ts" bson:"updated_ts"`
+
+	// PublicKey is the base64-encoded Ed25519 public key the device
+	// uploaded when it provisioned, used to verify the signed challenge
+	// response in PrepareUserSession. Empty for devices that provisioned
+	// before this field existed.
+	PublicKey string `json:"public_key,omitempty" bson:"public_key,omitempty"`
+}
@@ -0,0 +1,32 @@
+// Copyright 2020 Northern.tech AS
+//
+//    Licensed under the Apache License, Version 2.0 (the "License");
+//    you may not use this file except in compliance with the License.
+//    You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS,
+//    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//    See the License for the specific language governing permissions and
+//    limitations under the License.
+
+package model
+
+const (
+	TypeShell       = "shell"
+	TypeFileTransfer = "filetransfer"
+	TypePortForward = "portforward"
+)
+
+// Message is the payload exchanged between a device and the management
+// API over NATS, msgpack-encoded on the wire.
+type Message struct {
+	Type string `msgpack:"type"`
+	Data []byte `msgpack:"data"`
+	// Seq is the stream sequence number assigned by JetStream when the
+	// message was published. It is left at zero for deployments still
+	// running on core NATS, where delivery is not persisted.
+	Seq uint64 `msgpack:"seq,omitempty"`
+}
@@ -0,0 +1,41 @@
+// Copyright 2020 Northern.tech AS
+//
+//    Licensed under the Apache License, Version 2.0 (the "License");
+//    you may not use this file except in compliance with the License.
+//    You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS,
+//    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//    See the License for the specific language governing permissions and
+//    limitations under the License.
+
+package model
+
+import "time"
+
+const (
+	SessionStatusConnected    = "connected"
+	SessionStatusDisconnected = "disconnected"
+)
+
+// Session represents a single remote terminal session between a user and a device.
+type Session struct {
+	ID        string    `json:"id" bson:"_id"`
+	UserID    string    `json:"user_id" bson:"user_id"`
+	DeviceID  string    `json:"device_id" bson:"device_id"`
+	Status    string    `json:"status" bson:"status"`
+	CreatedTs time.Time `json:"created_ts" bson:"created_ts"`
+	UpdatedTs time.Time `json:"updated_ts" bson:"updated_ts"`
+
+	// DeviceSeq and ManagementSeq are the JetStream sequence numbers of
+	// the last message published by, respectively, the device and
+	// management side of this session that was delivered to the other
+	// side. They are only meaningful when deviceconnect is running in
+	// JetStream mode, and let a reconnecting side resume exactly where
+	// it left off via ResumeUserSession.
+	DeviceSeq     uint64 `json:"device_seq" bson:"device_seq"`
+	ManagementSeq uint64 `json:"management_seq" bson:"management_seq"`
+}
@@ -0,0 +1,51 @@
+// Copyright 2020 Northern.tech AS
+//
+//    Licensed under the Apache License, Version 2.0 (the "License");
+//    you may not use this file except in compliance with the License.
+//    You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS,
+//    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//    See the License for the specific language governing permissions and
+//    limitations under the License.
+
+package store
+
+import (
+	"context"
+
+	"github.com/mendersoftware/deviceconnect/model"
+)
+
+// DataStore is the interface for the persistence layer used by the app.
+//
+//go:generate ../utils/mockgen.sh
+type DataStore interface {
+	Ping(ctx context.Context) error
+
+	ProvisionTenant(ctx context.Context, tenantID string) error
+	// ProvisionDevice records a newly provisioned device and, if
+	// publicKey is non-empty, the public key it generated for
+	// PrepareUserSession challenges - sparing it a separate
+	// RotateDeviceKey call right after provisioning.
+	ProvisionDevice(ctx context.Context, tenantID string, deviceID string, publicKey string) error
+	DeleteDevice(ctx context.Context, tenantID string, deviceID string) error
+	GetDevice(ctx context.Context, tenantID string, deviceID string) (*model.Device, error)
+	UpdateDeviceStatus(ctx context.Context, tenantID string, deviceID string, status string) error
+	// RotateDeviceKey replaces a device's public key, e.g. after it
+	// generates a new keypair locally. Future PrepareUserSession
+	// challenges are verified against newPub.
+	RotateDeviceKey(ctx context.Context, tenantID string, deviceID string, newPub string) error
+
+	UpsertSession(ctx context.Context, tenantID string, userID string, deviceID string) (*model.Session, error)
+	GetSession(ctx context.Context, tenantID string, sessionID string) (*model.Session, error)
+	UpdateSessionStatus(ctx context.Context, tenantID string, sessionID string, status string) error
+	// UpdateSessionSeq records the last JetStream sequence number
+	// delivered on the given side ("device" or "management") of a
+	// session, so a reconnecting client can resume from where it left
+	// off via the app's ResumeUserSession.
+	UpdateSessionSeq(ctx context.Context, tenantID string, sessionID string, side string, seq uint64) error
+}